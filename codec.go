@@ -0,0 +1,102 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// AttrCodec is a pluggable encoder/decoder for a scalar attribute type,
+// selected by name via an option on an `attr` tag, e.g.
+// `jsonapi:"attr,homepage,url"`; the first option that names a registered
+// codec is the one used. Marshal receives the struct field's
+// reflect.Value and returns the value to place in the wire `attributes`
+// object; Unmarshal receives that decoded wire value and sets dst, which is
+// always addressable.
+type AttrCodec interface {
+	Marshal(v reflect.Value) (interface{}, error)
+	Unmarshal(raw interface{}, dst reflect.Value) error
+}
+
+var (
+	attrCodecsMu sync.RWMutex
+	attrCodecs   = map[string]AttrCodec{}
+)
+
+// RegisterAttrCodec associates name, the tag option that selects this codec,
+// with codec. Registering under the name of a previously-registered codec
+// replaces it, so built-in behaviors such as "iso8601" can be overridden.
+func RegisterAttrCodec(name string, codec AttrCodec) {
+	attrCodecsMu.Lock()
+	defer attrCodecsMu.Unlock()
+	attrCodecs[name] = codec
+}
+
+func lookupAttrCodec(name string) (AttrCodec, bool) {
+	attrCodecsMu.RLock()
+	defer attrCodecsMu.RUnlock()
+	codec, ok := attrCodecs[name]
+	return codec, ok
+}
+
+func init() {
+	RegisterAttrCodec(annotationISO8601, iso8601Codec{})
+	RegisterAttrCodec(annotationRFC3339, rfc3339Codec{})
+	RegisterAttrCodec(annotationJSONify, jsonifyCodec{})
+}
+
+// iso8601Codec backs the `,iso8601` tag option.
+type iso8601Codec struct{}
+
+func (iso8601Codec) Marshal(v reflect.Value) (interface{}, error) {
+	t, ok := timeValue(v)
+	if !ok {
+		return nil, fmt.Errorf("iso8601 attribute was not a time.Time")
+	}
+	return t.UTC().Format(iso8601TimeFormat), nil
+}
+
+func (iso8601Codec) Unmarshal(raw interface{}, dst reflect.Value) error {
+	return unmarshalISO8601(raw, dst)
+}
+
+// rfc3339Codec backs the `,rfc3339` tag option.
+type rfc3339Codec struct{}
+
+func (rfc3339Codec) Marshal(v reflect.Value) (interface{}, error) {
+	t, ok := timeValue(v)
+	if !ok {
+		return nil, fmt.Errorf("rfc3339 attribute was not a time.Time")
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+func (rfc3339Codec) Unmarshal(raw interface{}, dst reflect.Value) error {
+	return unmarshalRFC3339(raw, dst)
+}
+
+// jsonifyCodec backs the `,jsonify` tag option, which drives a field's own
+// json.Marshaler/json.Unmarshaler instead of a fixed time layout.
+type jsonifyCodec struct{}
+
+func (jsonifyCodec) Marshal(v reflect.Value) (interface{}, error) {
+	marshaler, ok := addrInterface(v).(json.Marshaler)
+	if !ok {
+		return nil, fmt.Errorf("jsonify attribute does not implement json.Marshaler")
+	}
+	b, err := marshaler.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (jsonifyCodec) Unmarshal(raw interface{}, dst reflect.Value) error {
+	return unmarshalJSONify(raw, dst)
+}
@@ -0,0 +1,74 @@
+package jsonapi
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// urlCodec is a user-registered codec demonstrating that RegisterAttrCodec
+// lets callers add semantic scalar types without touching the library.
+type urlCodec struct{}
+
+func (urlCodec) Marshal(v reflect.Value) (interface{}, error) {
+	return v.String(), nil
+}
+
+func (urlCodec) Unmarshal(raw interface{}, dst reflect.Value) error {
+	s, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("url attribute was not a string")
+	}
+	if _, err := url.Parse(s); err != nil {
+		return fmt.Errorf("url attribute was not a valid URL: %w", err)
+	}
+	dst.SetString(s)
+	return nil
+}
+
+func init() {
+	RegisterAttrCodec("url", urlCodec{})
+}
+
+func TestCustomAttrCodecRoundTrip(t *testing.T) {
+	site := &Site{ID: "1", Homepage: "https://example.com"}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, site); err != nil {
+		t.Fatalf("MarshalPayload() error = %v", err)
+	}
+
+	roundTripped := new(Site)
+	if err := UnmarshalPayload(bytes.NewReader(buf.Bytes()), roundTripped); err != nil {
+		t.Fatalf("UnmarshalPayload() error = %v", err)
+	}
+
+	if roundTripped.Homepage != site.Homepage {
+		t.Errorf("Homepage = %q, want %q", roundTripped.Homepage, site.Homepage)
+	}
+}
+
+func TestBuiltinCodecsStillWork(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	original := &Timestamp{ID: 1, Time: ts, Next: &ts}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, original); err != nil {
+		t.Fatalf("MarshalPayload() error = %v", err)
+	}
+
+	roundTripped := new(Timestamp)
+	if err := UnmarshalPayload(bytes.NewReader(buf.Bytes()), roundTripped); err != nil {
+		t.Fatalf("UnmarshalPayload() error = %v", err)
+	}
+
+	if !roundTripped.Time.Equal(original.Time) {
+		t.Errorf("Time = %v, want %v", roundTripped.Time, original.Time)
+	}
+	if !roundTripped.Next.Equal(*original.Next) {
+		t.Errorf("Next = %v, want %v", roundTripped.Next, *original.Next)
+	}
+}
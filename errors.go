@@ -0,0 +1,35 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ErrorsPayload is the top level document structure for JSON:API error
+// responses, i.e. `{"errors": [...]}`.
+type ErrorsPayload struct {
+	Errors []*ErrorObject `json:"errors"`
+}
+
+// ErrorObject is a JSON:API error object as described in
+// http://jsonapi.org/format/#error-objects.
+type ErrorObject struct {
+	ID     string                  `json:"id,omitempty"`
+	Title  string                  `json:"title,omitempty"`
+	Detail string                  `json:"detail,omitempty"`
+	Status string                  `json:"status,omitempty"`
+	Code   string                  `json:"code,omitempty"`
+	Meta   *map[string]interface{} `json:"meta,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *ErrorObject) Error() string {
+	return fmt.Sprintf("Error: %s %s", e.Title, e.Detail)
+}
+
+// MarshalErrors writes a JSON:API errors payload for the given error
+// objects to w.
+func MarshalErrors(w io.Writer, errorObjects []*ErrorObject) error {
+	return json.NewEncoder(w).Encode(&ErrorsPayload{Errors: errorObjects})
+}
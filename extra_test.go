@@ -0,0 +1,96 @@
+package jsonapi
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalExtraCatchAll(t *testing.T) {
+	in := bytes.NewReader([]byte(`{
+		"data": {
+			"type": "widgets",
+			"id": "1",
+			"attributes": {
+				"name": "gizmo",
+				"color": "red",
+				"dimensions": {"width": 3, "height": 4}
+			}
+		}
+	}`))
+
+	widget := new(Widget)
+	if err := UnmarshalPayload(in, widget); err != nil {
+		t.Fatalf("UnmarshalPayload() error = %v", err)
+	}
+
+	if widget.Name != "gizmo" {
+		t.Errorf("Name = %q, want %q", widget.Name, "gizmo")
+	}
+	if _, ok := widget.Extra["name"]; ok {
+		t.Errorf("expected the statically-tagged %q attribute to be excluded from Extra, got %v", "name", widget.Extra)
+	}
+	if widget.Extra["color"] != "red" {
+		t.Errorf("Extra[color] = %v, want %q", widget.Extra["color"], "red")
+	}
+	dims, ok := widget.Extra["dimensions"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected Extra[dimensions] to be a nested object, got %T", widget.Extra["dimensions"])
+	}
+	if dims["width"] != float64(3) {
+		t.Errorf("dimensions.width = %v, want 3", dims["width"])
+	}
+}
+
+func TestMarshalExtraOverwritePrecedence(t *testing.T) {
+	widget := &Widget{
+		ID:   "1",
+		Name: "gizmo",
+		Extra: map[string]interface{}{
+			"name":  "should be ignored",
+			"color": "red",
+		},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, widget); err != nil {
+		t.Fatalf("MarshalPayload() error = %v", err)
+	}
+
+	roundTripped := new(Widget)
+	if err := UnmarshalPayload(bytes.NewReader(buf.Bytes()), roundTripped); err != nil {
+		t.Fatalf("UnmarshalPayload() error = %v", err)
+	}
+
+	if roundTripped.Name != "gizmo" {
+		t.Errorf("explicit field lost to Extra map: Name = %q, want %q", roundTripped.Name, "gizmo")
+	}
+	if roundTripped.Extra["color"] != "red" {
+		t.Errorf("Extra[color] = %v, want %q", roundTripped.Extra["color"], "red")
+	}
+}
+
+func TestExtraRoundTripStability(t *testing.T) {
+	original := &Widget{
+		ID:   "1",
+		Name: "gizmo",
+		Extra: map[string]interface{}{
+			"color": "red",
+			"tags":  []interface{}{"a", "b"},
+		},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, original); err != nil {
+		t.Fatalf("MarshalPayload() error = %v", err)
+	}
+
+	roundTripped := new(Widget)
+	if err := UnmarshalPayload(bytes.NewReader(buf.Bytes()), roundTripped); err != nil {
+		t.Fatalf("UnmarshalPayload() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(original.Extra, roundTripped.Extra) {
+		t.Errorf("Extra did not round-trip: got %#v, want %#v", roundTripped.Extra, original.Extra)
+	}
+}
@@ -42,14 +42,15 @@ type Car struct {
 
 type Post struct {
 	Blog
-	ID            uint64     `jsonapi:"primary,posts"             json:"id"`
-	BlogID        int        `jsonapi:"attr,blog_id"              json:"-"`
-	ClientID      string     `jsonapi:"client-id"                 json:"-"`
-	Title         string     `jsonapi:"attr,title"                json:"title"`
-	Body          string     `jsonapi:"attr,body"                 json:"body"`
-	PublishedAt   *EpochTime `jsonapi:"attr,published_at,jsonify" json:"published_at"`
-	Comments      []*Comment `jsonapi:"relation,comments"         json:"-"`
-	LatestComment *Comment   `jsonapi:"relation,latest_comment"   json:"-"`
+	ID            uint64        `jsonapi:"primary,posts"             json:"id"`
+	BlogID        int           `jsonapi:"attr,blog_id"              json:"-"`
+	ClientID      string        `jsonapi:"client-id"                 json:"-"`
+	Title         string        `jsonapi:"attr,title"                json:"title"`
+	Body          string        `jsonapi:"attr,body"                 json:"body"`
+	PublishedAt   *EpochTime    `jsonapi:"attr,published_at,jsonify" json:"published_at"`
+	Comments      []*Comment    `jsonapi:"relation,comments"         json:"-"`
+	LatestComment *Comment      `jsonapi:"relation,latest_comment"   json:"-"`
+	Mentions      []interface{} `jsonapi:"relation,mentions,poly" json:"-"`
 }
 
 // Obviously you're unlikely to do this, but protobuf does... so test it here
@@ -87,7 +88,7 @@ func (p *Post) UnmarshalJSON(data []byte) error {
 	if err != nil {
 		return err
 	}
-	p.PublishedAt = &EpochTime{Seconds: nanos/int64(time.Second), Nanos: int32(math.Mod(float64(nanos), float64(time.Second)))}
+	p.PublishedAt = &EpochTime{Seconds: nanos / int64(time.Second), Nanos: int32(math.Mod(float64(nanos), float64(time.Second)))}
 	return nil
 }
 
@@ -233,3 +234,14 @@ type CustomAttributeTypes struct {
 	Float  CustomFloatType  `jsonapi:"attr,float"`
 	String CustomStringType `jsonapi:"attr,string"`
 }
+
+type Widget struct {
+	ID    string                 `jsonapi:"primary,widgets"`
+	Name  string                 `jsonapi:"attr,name"`
+	Extra map[string]interface{} `jsonapi:"extra"`
+}
+
+type Site struct {
+	ID       string `jsonapi:"primary,sites"`
+	Homepage string `jsonapi:"attr,homepage,url"`
+}
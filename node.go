@@ -0,0 +1,60 @@
+package jsonapi
+
+// Node is the generic JSON:API resource object as it appears "on the wire" --
+// either as the top level `data` member or as an entry in `included`.
+type Node struct {
+	Type          string                 `json:"type"`
+	ID            string                 `json:"id,omitempty"`
+	ClientID      string                 `json:"client-id,omitempty"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	Relationships map[string]interface{} `json:"relationships,omitempty"`
+	Links         *Links                 `json:"links,omitempty"`
+	Meta          *Meta                  `json:"meta,omitempty"`
+}
+
+// OnePayload is used to represent a generic JSON:API payload where a single
+// resource (`Data`) is present.
+type OnePayload struct {
+	Data     *Node   `json:"data"`
+	Included []*Node `json:"included,omitempty"`
+	Links    *Links  `json:"links,omitempty"`
+	Meta     *Meta   `json:"meta,omitempty"`
+}
+
+// ManyPayload is used to represent a generic JSON:API payload where a
+// collection of resources (`Data`) is present.
+type ManyPayload struct {
+	Data     []*Node `json:"data"`
+	Included []*Node `json:"included,omitempty"`
+	Links    *Links  `json:"links,omitempty"`
+	Meta     *Meta   `json:"meta,omitempty"`
+}
+
+// RelationshipOneNode is used to represent a generic has-one JSON:API
+// relation.
+type RelationshipOneNode struct {
+	Data  *Node  `json:"data"`
+	Links *Links `json:"links,omitempty"`
+	Meta  *Meta  `json:"meta,omitempty"`
+}
+
+// RelationshipManyNode is used to represent a generic has-many JSON:API
+// relation.
+type RelationshipManyNode struct {
+	Data  []*Node `json:"data"`
+	Links *Links  `json:"links,omitempty"`
+	Meta  *Meta   `json:"meta,omitempty"`
+}
+
+// Links is a JSON:API `links` object, e.g. `{"self": "...", "related": "..."}`.
+// Values may be a bare string or a Link object.
+type Links map[string]interface{}
+
+// Link is the expanded JSON:API links member that carries its own Meta.
+type Link struct {
+	Href string `json:"href"`
+	Meta Meta   `json:"meta,omitempty"`
+}
+
+// Meta is a JSON:API `meta` object.
+type Meta map[string]interface{}
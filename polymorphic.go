@@ -0,0 +1,39 @@
+package jsonapi
+
+import (
+	"reflect"
+	"sync"
+)
+
+// polymorphicTypes maps a JSON:API resource `type` member to the concrete Go
+// type that represents it inside a `poly` relationship, e.g. a `Comment`
+// registered under "comments". It lets a single `[]interface{}` (or named
+// interface) field hold resources of more than one concrete type, resolved
+// by the wire `type` member instead of a single fixed Go type.
+var (
+	polymorphicTypesMu sync.RWMutex
+	polymorphicTypes   = map[string]reflect.Type{}
+)
+
+// RegisterPolymorphicType associates typeName, the JSON:API resource `type`
+// string as it appears on the wire, with model, a pointer to the concrete
+// struct `UnmarshalPayload`/`UnmarshalManyPayload` should allocate whenever
+// they encounter that type inside a relation field tagged `poly` (or a
+// relation field whose Go type is an interface).
+func RegisterPolymorphicType(typeName string, model interface{}) {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	polymorphicTypesMu.Lock()
+	defer polymorphicTypesMu.Unlock()
+	polymorphicTypes[typeName] = t
+}
+
+func lookupPolymorphicType(typeName string) (reflect.Type, bool) {
+	polymorphicTypesMu.RLock()
+	defer polymorphicTypesMu.RUnlock()
+	t, ok := polymorphicTypes[typeName]
+	return t, ok
+}
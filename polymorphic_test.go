@@ -0,0 +1,128 @@
+package jsonapi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func init() {
+	RegisterPolymorphicType("comments", &Comment{})
+	RegisterPolymorphicType("blogs", &Blog{})
+}
+
+func TestMarshalPolymorphicMixedSlice(t *testing.T) {
+	post := &Post{
+		ID:    1,
+		Title: "hello",
+		Mentions: []interface{}{
+			&Comment{ID: 1, Body: "nice post"},
+			&Blog{ID: 2, Title: "related blog"},
+		},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, post); err != nil {
+		t.Fatalf("MarshalPayload() error = %v", err)
+	}
+
+	body := buf.String()
+	if !strings.Contains(body, `"type":"comments"`) {
+		t.Errorf("expected marshaled mentions to include a comments resource, got %s", body)
+	}
+	if !strings.Contains(body, `"type":"blogs"`) {
+		t.Errorf("expected marshaled mentions to include a blogs resource, got %s", body)
+	}
+}
+
+func TestUnmarshalPolymorphicMixedSlice(t *testing.T) {
+	in := strings.NewReader(`{
+		"data": {
+			"type": "posts",
+			"id": "1",
+			"attributes": {"title": "hello", "body": "world"},
+			"relationships": {
+				"mentions": {
+					"data": [
+						{"type": "comments", "id": "9"},
+						{"type": "blogs", "id": "4"}
+					]
+				}
+			}
+		},
+		"included": [
+			{"type": "comments", "id": "9", "attributes": {"body": "nice post"}},
+			{"type": "blogs", "id": "4", "attributes": {"title": "related blog"}}
+		]
+	}`)
+
+	post := new(Post)
+	if err := UnmarshalPayload(in, post); err != nil {
+		t.Fatalf("UnmarshalPayload() error = %v", err)
+	}
+
+	if len(post.Mentions) != 2 {
+		t.Fatalf("expected 2 mentions, got %d", len(post.Mentions))
+	}
+
+	comment, ok := post.Mentions[0].(*Comment)
+	if !ok {
+		t.Fatalf("expected first mention to be *Comment, got %T", post.Mentions[0])
+	}
+	if comment.Body != "nice post" {
+		t.Errorf("comment.Body = %q, want %q", comment.Body, "nice post")
+	}
+
+	blog, ok := post.Mentions[1].(*Blog)
+	if !ok {
+		t.Fatalf("expected second mention to be *Blog, got %T", post.Mentions[1])
+	}
+	if blog.Title != "related blog" {
+		t.Errorf("blog.Title = %q, want %q", blog.Title, "related blog")
+	}
+}
+
+func TestUnmarshalPolymorphicNilRelation(t *testing.T) {
+	in := strings.NewReader(`{
+		"data": {
+			"type": "posts",
+			"id": "1",
+			"attributes": {"title": "hello", "body": "world"},
+			"relationships": {
+				"mentions": {"data": null}
+			}
+		}
+	}`)
+
+	post := new(Post)
+	if err := UnmarshalPayload(in, post); err != nil {
+		t.Fatalf("UnmarshalPayload() error = %v", err)
+	}
+	if post.Mentions != nil {
+		t.Errorf("expected Mentions to stay nil, got %v", post.Mentions)
+	}
+}
+
+func TestUnmarshalPolymorphicUnregisteredType(t *testing.T) {
+	in := strings.NewReader(`{
+		"data": {
+			"type": "posts",
+			"id": "1",
+			"attributes": {"title": "hello", "body": "world"},
+			"relationships": {
+				"mentions": {
+					"data": [{"type": "widgets", "id": "1"}]
+				}
+			}
+		}
+	}`)
+
+	post := new(Post)
+	err := UnmarshalPayload(in, post)
+	if err == nil {
+		t.Fatal("expected an error for an unregistered polymorphic type, got nil")
+	}
+	if !strings.Contains(err.Error(), "widgets") {
+		t.Errorf("expected error to mention the unregistered type, got %v", err)
+	}
+}
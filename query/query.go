@@ -0,0 +1,152 @@
+// Package query parses the JSON:API query-string conventions -- `filter`,
+// `sort`, `page`, `fields`, and `include` -- off an incoming request into a
+// strongly-typed Query that a handler (or jsonapi.MarshalPayloadWithQuery)
+// can act on directly instead of re-parsing url.Values by hand.
+package query
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Query is a parsed JSON:API request query.
+type Query struct {
+	Include []string
+	Fields  map[string][]string
+	Sort    []SortField
+	Filter  map[string][]string
+	Page    PageSpec
+}
+
+// SortField is one entry of the `sort` query parameter, e.g. `sort=-created_at`
+// parses to SortField{Name: "created_at", Desc: true}.
+type SortField struct {
+	Name string
+	Desc bool
+}
+
+// PageSpec covers both pagination conventions seen across JSON:API servers:
+// offset/limit (Offset, Limit) and page-number/size (Number, Size), plus an
+// opaque Cursor for cursor-based pagination.
+type PageSpec struct {
+	Number int
+	Size   int
+	Offset int
+	Limit  int
+	Cursor string
+}
+
+// Parse parses the JSON:API query parameters off r.URL.
+func Parse(r *http.Request) (*Query, error) {
+	return ParseValues(r.URL.Query())
+}
+
+// ParseValues parses the JSON:API query conventions out of values, as
+// produced by (*url.URL).Query() or url.ParseQuery.
+func ParseValues(values url.Values) (*Query, error) {
+	q := &Query{
+		Fields: map[string][]string{},
+		Filter: map[string][]string{},
+	}
+
+	if include := values.Get("include"); include != "" {
+		q.Include = splitCSV(include)
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		for _, name := range splitCSV(sort) {
+			if strings.HasPrefix(name, "-") {
+				q.Sort = append(q.Sort, SortField{Name: strings.TrimPrefix(name, "-"), Desc: true})
+			} else {
+				q.Sort = append(q.Sort, SortField{Name: name})
+			}
+		}
+	}
+
+	for key, vals := range values {
+		name, ok := bracketed(key, "fields")
+		if ok {
+			q.Fields[name] = append(q.Fields[name], splitCSVAll(vals)...)
+			continue
+		}
+		if name, ok := bracketed(key, "filter"); ok {
+			q.Filter[name] = append(q.Filter[name], vals...)
+			continue
+		}
+		if name, ok := bracketed(key, "page"); ok {
+			if len(vals) == 0 {
+				continue
+			}
+			if err := q.Page.set(name, vals[len(vals)-1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return q, nil
+}
+
+func (p *PageSpec) set(name, value string) error {
+	switch name {
+	case "number":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("query: page[number]: %w", err)
+		}
+		p.Number = n
+	case "size":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("query: page[size]: %w", err)
+		}
+		p.Size = n
+	case "offset":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("query: page[offset]: %w", err)
+		}
+		p.Offset = n
+	case "limit":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("query: page[limit]: %w", err)
+		}
+		p.Limit = n
+	case "cursor":
+		p.Cursor = value
+	default:
+		return fmt.Errorf("query: unsupported page parameter %q", name)
+	}
+	return nil
+}
+
+// bracketed reports whether key is of the form "prefix[name]" and, if so,
+// returns name.
+func bracketed(key, prefix string) (string, bool) {
+	if !strings.HasPrefix(key, prefix+"[") || !strings.HasSuffix(key, "]") {
+		return "", false
+	}
+	return key[len(prefix)+1 : len(key)-1], true
+}
+
+func splitCSV(s string) []string {
+	parts := strings.Split(s, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func splitCSVAll(vals []string) []string {
+	out := make([]string, 0, len(vals))
+	for _, v := range vals {
+		out = append(out, splitCSV(v)...)
+	}
+	return out
+}
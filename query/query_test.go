@@ -0,0 +1,77 @@
+package query
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func mustParse(t *testing.T, raw string) *Query {
+	t.Helper()
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		t.Fatalf("url.ParseQuery(%q) error = %v", raw, err)
+	}
+	q, err := ParseValues(values)
+	if err != nil {
+		t.Fatalf("ParseValues() error = %v", err)
+	}
+	return q
+}
+
+func TestParseValuesInclude(t *testing.T) {
+	q := mustParse(t, "include=posts.comments,posts.author")
+	want := []string{"posts.comments", "posts.author"}
+	if !reflect.DeepEqual(q.Include, want) {
+		t.Errorf("Include = %v, want %v", q.Include, want)
+	}
+}
+
+func TestParseValuesFields(t *testing.T) {
+	q := mustParse(t, "fields[posts]=title,body&fields[comments]=body")
+	if !reflect.DeepEqual(q.Fields["posts"], []string{"title", "body"}) {
+		t.Errorf("Fields[posts] = %v", q.Fields["posts"])
+	}
+	if !reflect.DeepEqual(q.Fields["comments"], []string{"body"}) {
+		t.Errorf("Fields[comments] = %v", q.Fields["comments"])
+	}
+}
+
+func TestParseValuesSort(t *testing.T) {
+	q := mustParse(t, "sort=-created_at,title")
+	want := []SortField{{Name: "created_at", Desc: true}, {Name: "title"}}
+	if !reflect.DeepEqual(q.Sort, want) {
+		t.Errorf("Sort = %+v, want %+v", q.Sort, want)
+	}
+}
+
+func TestParseValuesFilter(t *testing.T) {
+	q := mustParse(t, "filter[title]=hello&filter[status]=draft,published")
+	if !reflect.DeepEqual(q.Filter["title"], []string{"hello"}) {
+		t.Errorf("Filter[title] = %v", q.Filter["title"])
+	}
+	if !reflect.DeepEqual(q.Filter["status"], []string{"draft,published"}) {
+		t.Errorf("Filter[status] = %v", q.Filter["status"])
+	}
+}
+
+func TestParseValuesPageOffsetLimit(t *testing.T) {
+	q := mustParse(t, "page[offset]=20&page[limit]=10")
+	if q.Page.Offset != 20 || q.Page.Limit != 10 {
+		t.Errorf("Page = %+v, want Offset=20 Limit=10", q.Page)
+	}
+}
+
+func TestParseValuesPageCursor(t *testing.T) {
+	q := mustParse(t, "page[cursor]=abc123&page[size]=5")
+	if q.Page.Cursor != "abc123" || q.Page.Size != 5 {
+		t.Errorf("Page = %+v, want Cursor=abc123 Size=5", q.Page)
+	}
+}
+
+func TestParseValuesInvalidPageNumber(t *testing.T) {
+	_, err := ParseValues(url.Values{"page[number]": {"not-a-number"}})
+	if err == nil {
+		t.Fatal("expected an error for a non-numeric page[number]")
+	}
+}
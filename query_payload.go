@@ -0,0 +1,138 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/confluentinc/jsonapi/query"
+)
+
+// MarshalPayloadWithQuery writes a JSON:API payload for model shaped by q:
+// sparse fieldsets (q.Fields) restrict which attributes and relationships
+// are emitted per resource type, q.Include selects which relationships are
+// sideloaded into the top-level `included` array -- dotted paths such as
+// "posts.comments" reach into a relationship's own relationships -- and
+// baseURL seeds the top-level `links` member with the active page, sort,
+// and filter reflected back to the caller.
+func MarshalPayloadWithQuery(w io.Writer, model interface{}, q *query.Query, baseURL string) error {
+	filter := &nodeFilter{tree: buildIncludeTree(q.Include), fields: q.Fields}
+	included := make(map[string]*Node)
+
+	var data interface{}
+	if reflect.ValueOf(model).Kind() == reflect.Slice {
+		value := reflect.ValueOf(model)
+		nodes := make([]*Node, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			node, err := visitModelNode(value.Index(i).Interface(), &included, filter)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, node)
+		}
+		data = nodes
+	} else {
+		node, err := visitModelNode(model, &included, filter)
+		if err != nil {
+			return err
+		}
+		data = node
+	}
+
+	payload := map[string]interface{}{"data": data}
+	if includedNodes := nodeMapValues(included); includedNodes != nil {
+		payload["included"] = includedNodes
+	}
+	if links := linksFromQuery(q, baseURL); links != nil {
+		payload["links"] = links
+	}
+
+	return json.NewEncoder(w).Encode(payload)
+}
+
+// linksFromQuery surfaces the active page, sort, and filter back to the
+// caller as a JSON:API top-level `links` object, resolved against baseURL.
+// It returns nil when there is nothing to report. Names and values are
+// encoded via url.Values so filter/sort values containing "&", "%", "#",
+// "+", or spaces produce a valid, round-trippable URL instead of a mangled
+// one.
+func linksFromQuery(q *query.Query, baseURL string) *Links {
+	if q == nil || baseURL == "" {
+		return nil
+	}
+
+	values := url.Values{}
+	if len(q.Sort) > 0 {
+		parts := make([]string, 0, len(q.Sort))
+		for _, s := range q.Sort {
+			if s.Desc {
+				parts = append(parts, "-"+s.Name)
+			} else {
+				parts = append(parts, s.Name)
+			}
+		}
+		values.Set("sort", strings.Join(parts, ","))
+	}
+	for name, vals := range q.Filter {
+		values.Set("filter["+name+"]", strings.Join(vals, ","))
+	}
+	for name, val := range pageQueryParams(q.Page) {
+		values.Set(name, val)
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	self := baseURL + "?" + values.Encode()
+	return &Links{"self": self}
+}
+
+func pageQueryParams(p query.PageSpec) map[string]string {
+	out := make(map[string]string)
+	if p.Number != 0 {
+		out["page[number]"] = strconv.Itoa(p.Number)
+	}
+	if p.Size != 0 {
+		out["page[size]"] = strconv.Itoa(p.Size)
+	}
+	if p.Offset != 0 {
+		out["page[offset]"] = strconv.Itoa(p.Offset)
+	}
+	if p.Limit != 0 {
+		out["page[limit]"] = strconv.Itoa(p.Limit)
+	}
+	if p.Cursor != "" {
+		out["page[cursor]"] = p.Cursor
+	}
+	return out
+}
+
+// includeNode is one level of the tree built from dotted Include paths,
+// e.g. ["posts.comments", "posts.author"] becomes a "posts" node with
+// "comments" and "author" children.
+type includeNode struct {
+	children map[string]*includeNode
+}
+
+func buildIncludeTree(paths []string) *includeNode {
+	root := &includeNode{children: map[string]*includeNode{}}
+	for _, p := range paths {
+		cur := root
+		for _, seg := range strings.Split(p, ".") {
+			if seg == "" {
+				continue
+			}
+			child, ok := cur.children[seg]
+			if !ok {
+				child = &includeNode{children: map[string]*includeNode{}}
+				cur.children[seg] = child
+			}
+			cur = child
+		}
+	}
+	return root
+}
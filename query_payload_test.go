@@ -0,0 +1,169 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/confluentinc/jsonapi/query"
+)
+
+func TestMarshalPayloadWithQuerySparseFieldset(t *testing.T) {
+	blog := &Blog{ID: 1, Title: "hello", ViewCount: 42}
+	q := &query.Query{Fields: map[string][]string{"blogs": {"title"}}}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayloadWithQuery(buf, blog, q, ""); err != nil {
+		t.Fatalf("MarshalPayloadWithQuery() error = %v", err)
+	}
+
+	var decoded struct {
+		Data struct {
+			Attributes map[string]interface{} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if _, ok := decoded.Data.Attributes["title"]; !ok {
+		t.Errorf("expected requested field %q to be present, attributes = %v", "title", decoded.Data.Attributes)
+	}
+	if _, ok := decoded.Data.Attributes["view_count"]; ok {
+		t.Errorf("expected field %q to be excluded by the sparse fieldset, attributes = %v", "view_count", decoded.Data.Attributes)
+	}
+}
+
+func TestMarshalPayloadWithQueryInclude(t *testing.T) {
+	post := &Post{
+		ID:            1,
+		Title:         "hello",
+		LatestComment: &Comment{ID: 9, Body: "nice"},
+		Comments:      []*Comment{{ID: 9, Body: "nice"}},
+	}
+
+	// Only "latest_comment" is requested, so "comments" should still show
+	// linkage but must not be sideloaded into `included`.
+	q := &query.Query{Include: []string{"latest_comment"}}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayloadWithQuery(buf, post, q, ""); err != nil {
+		t.Fatalf("MarshalPayloadWithQuery() error = %v", err)
+	}
+
+	var decoded struct {
+		Included []struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"included"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if len(decoded.Included) != 1 {
+		t.Fatalf("expected exactly 1 included resource, got %d: %+v", len(decoded.Included), decoded.Included)
+	}
+	if decoded.Included[0].Type != "comments" {
+		t.Errorf("included[0].Type = %q, want %q", decoded.Included[0].Type, "comments")
+	}
+}
+
+func TestMarshalPayloadWithQueryIncludeDottedPath(t *testing.T) {
+	blog := &Blog{
+		ID:    1,
+		Title: "hello",
+		Posts: []*Post{
+			{ID: 2, Title: "post", Comments: []*Comment{{ID: 9, Body: "nice"}}},
+		},
+	}
+
+	q := &query.Query{Include: []string{"posts.comments"}}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayloadWithQuery(buf, blog, q, ""); err != nil {
+		t.Fatalf("MarshalPayloadWithQuery() error = %v", err)
+	}
+
+	var decoded struct {
+		Included []struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"included"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	seen := map[string]bool{}
+	for _, inc := range decoded.Included {
+		seen[inc.Type+","+inc.ID] = true
+	}
+	if !seen["posts,2"] {
+		t.Errorf("expected the first include path segment %q to be sideloaded, included = %+v", "posts,2", decoded.Included)
+	}
+	if !seen["comments,9"] {
+		t.Errorf("expected the second include path segment %q to be sideloaded, included = %+v", "comments,9", decoded.Included)
+	}
+}
+
+func TestMarshalPayloadWithQueryLinks(t *testing.T) {
+	blog := &Blog{ID: 1, Title: "hello"}
+	q := &query.Query{
+		Sort: []query.SortField{{Name: "created_at", Desc: true}},
+		Page: query.PageSpec{Offset: 20, Limit: 10},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayloadWithQuery(buf, blog, q, "https://example.com/api/blogs"); err != nil {
+		t.Fatalf("MarshalPayloadWithQuery() error = %v", err)
+	}
+
+	var decoded struct {
+		Links struct {
+			Self string `json:"self"`
+		} `json:"links"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	self, err := url.Parse(decoded.Links.Self)
+	if err != nil {
+		t.Fatalf("links.self %q is not a valid URL: %v", decoded.Links.Self, err)
+	}
+	if got := self.Query().Get("sort"); got != "-created_at" {
+		t.Errorf("sort = %q, want %q", got, "-created_at")
+	}
+	if got := self.Query().Get("page[offset]"); got != "20" {
+		t.Errorf("page[offset] = %q, want %q", got, "20")
+	}
+}
+
+func TestMarshalPayloadWithQueryLinksEscapesFilterValues(t *testing.T) {
+	blog := &Blog{ID: 1, Title: "hello"}
+	q := &query.Query{Filter: map[string][]string{"title": {"hello world & friends"}}}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayloadWithQuery(buf, blog, q, "https://example.com/api/blogs"); err != nil {
+		t.Fatalf("MarshalPayloadWithQuery() error = %v", err)
+	}
+
+	var decoded struct {
+		Links struct {
+			Self string `json:"self"`
+		} `json:"links"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	self, err := url.Parse(decoded.Links.Self)
+	if err != nil {
+		t.Fatalf("links.self %q is not a valid URL: %v", decoded.Links.Self, err)
+	}
+	if got := self.Query().Get("filter[title]"); got != "hello world & friends" {
+		t.Errorf("filter[title] = %q, want %q", got, "hello world & friends")
+	}
+}
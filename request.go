@@ -0,0 +1,436 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	unsupportedStructTagMsg = "Unsupported jsonapi tag annotation, %s"
+)
+
+const (
+	annotationPrimary   = "primary"
+	annotationClientID  = "client-id"
+	annotationDefault   = "attr"
+	annotationRelation  = "relation"
+	annotationOmitEmpty = "omitempty"
+	annotationISO8601   = "iso8601"
+	annotationRFC3339   = "rfc3339"
+	annotationJSONify   = "jsonify"
+	annotationPoly      = "poly"
+	annotationExtra     = "extra"
+	annotationSeperator = ","
+)
+
+const iso8601TimeFormat = "2006-01-02T15:04:05Z"
+
+// UnmarshalPayload reads a JSON:API single-resource payload from in and
+// populates model, which must be a pointer to a struct annotated with
+// `jsonapi` tags. Relationships that are sideloaded under `included` are
+// resolved and attached; relationships that only carry a resource
+// identifier are populated with that identifier only.
+func UnmarshalPayload(in io.Reader, model interface{}) error {
+	payload := new(OnePayload)
+	if err := json.NewDecoder(in).Decode(payload); err != nil {
+		return err
+	}
+	return unmarshalNode(payload.Data, reflect.ValueOf(model), newIncludeIndex(payload.Included, nil))
+}
+
+// UnmarshalManyPayload reads a JSON:API multi-resource payload from in,
+// returning one populated value of type t (a pointer type) per resource in
+// `data`.
+func UnmarshalManyPayload(in io.Reader, t reflect.Type) ([]interface{}, error) {
+	payload := new(ManyPayload)
+	if err := json.NewDecoder(in).Decode(payload); err != nil {
+		return nil, err
+	}
+
+	idx := newIncludeIndex(payload.Included, nil)
+
+	models := []interface{}{}
+	for _, data := range payload.Data {
+		model := reflect.New(t.Elem())
+		if err := unmarshalNode(data, model, idx); err != nil {
+			return nil, err
+		}
+		models = append(models, model.Interface())
+	}
+	return models, nil
+}
+
+func unmarshalNode(data *Node, model reflect.Value, idx *includeIndex) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("data is not a jsonapi representation of '%v'", model.Type())
+		}
+	}()
+
+	modelValue := model.Elem()
+	modelType := model.Type().Elem()
+
+	var er error
+	for i := 0; i < modelValue.NumField(); i++ {
+		fieldType := modelType.Field(i)
+		tag := fieldType.Tag.Get("jsonapi")
+		if tag == "" {
+			continue
+		}
+
+		fieldValue := modelValue.Field(i)
+
+		args := strings.Split(tag, annotationSeperator)
+		if len(args) < 1 {
+			er = fmt.Errorf(unsupportedStructTagMsg, tag)
+			break
+		}
+
+		annotation := args[0]
+
+		if (annotation == annotationPrimary || annotation == annotationClientID) && len(args) < 1 {
+			er = fmt.Errorf(unsupportedStructTagMsg, tag)
+			break
+		}
+
+		switch annotation {
+		case annotationPrimary:
+			if len(args) < 2 {
+				er = fmt.Errorf(unsupportedStructTagMsg, tag)
+				break
+			}
+			if data.ID == "" {
+				continue
+			}
+			if data.Type != args[1] {
+				er = fmt.Errorf("jsonapi: data.Type %q does not match expected resource type %q", data.Type, args[1])
+				break
+			}
+			if err := setPrimaryField(fieldValue, data.ID); err != nil {
+				er = err
+				break
+			}
+		case annotationClientID:
+			if data.ClientID == "" {
+				continue
+			}
+			fieldValue.Set(reflect.ValueOf(data.ClientID))
+		case annotationExtra:
+			if err := unmarshalExtraMap(data, modelType, fieldValue); err != nil {
+				er = err
+				break
+			}
+		case annotationDefault:
+			if len(args) < 2 {
+				er = fmt.Errorf(unsupportedStructTagMsg, tag)
+				break
+			}
+			attrName := args[1]
+			if attrName == "*" {
+				if err := unmarshalExtraMap(data, modelType, fieldValue); err != nil {
+					er = err
+					break
+				}
+				break
+			}
+			raw, ok := data.Attributes[attrName]
+			if !ok {
+				continue
+			}
+			if err := unmarshalAttribute(raw, args[2:], fieldValue); err != nil {
+				er = err
+				break
+			}
+		case annotationRelation:
+			if len(args) < 2 {
+				er = fmt.Errorf(unsupportedStructTagMsg, tag)
+				break
+			}
+			relName := args[1]
+			relData, ok := data.Relationships[relName]
+			if !ok {
+				continue
+			}
+			if err := unmarshalRelation(relData, args[2:], fieldValue, idx); err != nil {
+				er = err
+				break
+			}
+		default:
+			er = fmt.Errorf(unsupportedStructTagMsg, annotation)
+		}
+
+		if er != nil {
+			break
+		}
+	}
+
+	return er
+}
+
+func setPrimaryField(fieldValue reflect.Value, id string) error {
+	kind := fieldValue.Kind()
+	if kind == reflect.Ptr {
+		fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+		return setPrimaryField(fieldValue.Elem(), id)
+	}
+
+	switch kind {
+	case reflect.String:
+		fieldValue.SetString(id)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return err
+		}
+		fieldValue.SetInt(intID)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return err
+		}
+		fieldValue.SetUint(uintID)
+	default:
+		return fmt.Errorf("unsupported primary key type %s", fieldValue.Type())
+	}
+	return nil
+}
+
+func unmarshalAttribute(raw interface{}, options []string, fieldValue reflect.Value) (err error) {
+	value := reflect.ValueOf(raw)
+
+	fieldType := fieldValue.Type()
+	if fieldType.Kind() == reflect.Ptr && raw == nil {
+		return nil
+	}
+
+	for _, opt := range options {
+		if codec, ok := lookupAttrCodec(opt); ok {
+			return codec.Unmarshal(raw, fieldValue)
+		}
+	}
+
+	if fieldType.Kind() == reflect.Ptr {
+		fieldValue.Set(reflect.New(fieldType.Elem()))
+		fieldValue = fieldValue.Elem()
+		fieldType = fieldType.Elem()
+	}
+
+	if fieldType == reflect.TypeOf(time.Time{}) {
+		return unmarshalRFC3339(raw, fieldValue)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cannot assign %v to field of type %s", raw, fieldType)
+		}
+	}()
+
+	value = reflect.ValueOf(raw)
+	if !value.IsValid() {
+		return nil
+	}
+
+	if value.Type().ConvertibleTo(fieldType) {
+		fieldValue.Set(value.Convert(fieldType))
+		return nil
+	}
+
+	// json numbers decode as float64; allow conversion into any numeric kind.
+	switch fieldType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fieldValue.SetInt(int64(value.Float()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fieldValue.SetUint(uint64(value.Float()))
+	case reflect.Float32, reflect.Float64:
+		fieldValue.SetFloat(value.Float())
+	default:
+		return fmt.Errorf("cannot assign %v to field of type %s", raw, fieldType)
+	}
+	return nil
+}
+
+func unmarshalISO8601(raw interface{}, fieldValue reflect.Value) error {
+	str, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("iso8601 attribute was not a string")
+	}
+	t, err := time.Parse(iso8601TimeFormat, str)
+	if err != nil {
+		return err
+	}
+	return setTimeField(t, fieldValue)
+}
+
+func unmarshalRFC3339(raw interface{}, fieldValue reflect.Value) error {
+	str, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("rfc3339 attribute was not a string")
+	}
+	t, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		return err
+	}
+	return setTimeField(t, fieldValue)
+}
+
+func setTimeField(t time.Time, fieldValue reflect.Value) error {
+	if fieldValue.Kind() == reflect.Ptr {
+		fieldValue.Set(reflect.ValueOf(&t))
+		return nil
+	}
+	fieldValue.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// unmarshalJSONify re-marshals raw back to JSON bytes and delegates to the
+// field's own json.Unmarshaler; a field type without one is an error.
+func unmarshalJSONify(raw interface{}, fieldValue reflect.Value) error {
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	target := fieldValue
+	if target.Kind() == reflect.Ptr {
+		target.Set(reflect.New(target.Type().Elem()))
+		target = target.Elem()
+	}
+
+	unmarshaler, ok := target.Addr().Interface().(json.Unmarshaler)
+	if !ok {
+		return fmt.Errorf("%s does not implement json.Unmarshaler", target.Type())
+	}
+	return unmarshaler.UnmarshalJSON(b)
+}
+
+func unmarshalRelation(relData interface{}, options []string, fieldValue reflect.Value, idx *includeIndex) error {
+	relMap, ok := relData.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("relationship was not a JSON:API relationship object")
+	}
+	data, hasData := relMap["data"]
+	if !hasData || data == nil {
+		return nil
+	}
+
+	poly := isPolyOption(options)
+
+	switch fieldValue.Kind() {
+	case reflect.Slice:
+		rawNodes, ok := data.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected to-many relationship data to be an array")
+		}
+		isInterfaceElem := fieldValue.Type().Elem().Kind() == reflect.Interface
+		slice := reflect.MakeSlice(fieldValue.Type(), 0, len(rawNodes))
+		for _, rawNode := range rawNodes {
+			m, err := buildRelatedModel(rawNode, fieldValue.Type().Elem(), poly || isInterfaceElem, idx)
+			if err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, m)
+		}
+		fieldValue.Set(slice)
+	default:
+		isInterfaceField := fieldValue.Kind() == reflect.Interface
+		m, err := buildRelatedModel(data, fieldValue.Type(), poly || isInterfaceField, idx)
+		if err != nil {
+			return err
+		}
+		fieldValue.Set(m)
+	}
+	return nil
+}
+
+func isPolyOption(options []string) bool {
+	for _, opt := range options {
+		if opt == annotationPoly {
+			return true
+		}
+	}
+	return false
+}
+
+func buildRelatedModel(rawNode interface{}, fieldType reflect.Type, poly bool, idx *includeIndex) (reflect.Value, error) {
+	identifier, ok := rawNode.(map[string]interface{})
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("relationship data entry was not a resource identifier object")
+	}
+
+	resType, _ := identifier["type"].(string)
+	id, _ := identifier["id"].(string)
+
+	node := &Node{Type: resType, ID: id}
+	if full, ok := idx.lookup(resType, id); ok {
+		node = full
+	}
+
+	var elemType reflect.Type
+	isPtr := true
+	if poly {
+		registered, ok := lookupPolymorphicType(resType)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("jsonapi: no polymorphic type registered for resource type %q", resType)
+		}
+		elemType = registered
+	} else {
+		isPtr = fieldType.Kind() == reflect.Ptr
+		elemType = fieldType
+		if isPtr {
+			elemType = fieldType.Elem()
+		}
+	}
+
+	m := reflect.New(elemType)
+	if err := unmarshalNode(node, m, idx); err != nil {
+		return reflect.Value{}, err
+	}
+
+	if isPtr {
+		return m, nil
+	}
+	return m.Elem(), nil
+}
+
+// unmarshalExtraMap deposits every attribute in data.Attributes that isn't
+// bound to a named `attr` field on modelType into fieldValue, a
+// map[string]interface{} field tagged `jsonapi:"extra"` (or `attr,*`).
+func unmarshalExtraMap(data *Node, modelType reflect.Type, fieldValue reflect.Value) error {
+	if fieldValue.Type() != reflect.TypeOf(map[string]interface{}{}) {
+		return fmt.Errorf("extra attribute field must be of type map[string]interface{}")
+	}
+
+	known := knownAttrNames(modelType)
+	extra := make(map[string]interface{}, len(data.Attributes))
+	for k, v := range data.Attributes {
+		if known[k] {
+			continue
+		}
+		extra[k] = v
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+	fieldValue.Set(reflect.ValueOf(extra))
+	return nil
+}
+
+func knownAttrNames(modelType reflect.Type) map[string]bool {
+	known := make(map[string]bool)
+	for i := 0; i < modelType.NumField(); i++ {
+		tag := modelType.Field(i).Tag.Get("jsonapi")
+		if tag == "" {
+			continue
+		}
+		args := strings.Split(tag, annotationSeperator)
+		if len(args) >= 2 && args[0] == annotationDefault && args[1] != "*" {
+			known[args[1]] = true
+		}
+	}
+	return known
+}
@@ -0,0 +1,427 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Linkable is implemented by models that want to contribute a top-level
+// `links` object for their own resource.
+type Linkable interface {
+	JSONAPILinks() *Links
+}
+
+// RelationshipLinkable is implemented by models that want to contribute a
+// `links` object to one of their relationships.
+type RelationshipLinkable interface {
+	JSONAPIRelationshipLinks(relation string) *Links
+}
+
+// Metable is implemented by models that want to contribute a top-level
+// `meta` object for their own resource.
+type Metable interface {
+	JSONAPIMeta() *Meta
+}
+
+// RelationshipMetable is implemented by models that want to contribute a
+// `meta` object to one of their relationships.
+type RelationshipMetable interface {
+	JSONAPIRelationshipMeta(relation string) *Meta
+}
+
+// MarshalPayload writes a JSON:API payload for model to w. model may be a
+// pointer to a single annotated struct, or a slice (or pointer to slice) of
+// such structs, in which case a ManyPayload is written.
+func MarshalPayload(w io.Writer, model interface{}) error {
+	value := reflect.ValueOf(model)
+	if value.Kind() == reflect.Ptr && value.Elem().Kind() == reflect.Slice {
+		return marshalManyPayload(w, value.Elem().Interface())
+	}
+	switch value.Kind() {
+	case reflect.Slice:
+		return marshalManyPayload(w, model)
+	default:
+		payload, err := marshalOnePayload(model)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(w).Encode(payload)
+	}
+}
+
+func marshalOnePayload(model interface{}) (*OnePayload, error) {
+	included := make(map[string]*Node)
+	rootNode, err := visitModelNode(model, &included, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &OnePayload{Data: rootNode}
+	payload.Included = nodeMapValues(included)
+	return payload, nil
+}
+
+func marshalManyPayload(w io.Writer, models interface{}) error {
+	value := reflect.ValueOf(models)
+
+	included := make(map[string]*Node)
+	data := make([]*Node, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		node, err := visitModelNode(value.Index(i).Interface(), &included, nil)
+		if err != nil {
+			return err
+		}
+		data = append(data, node)
+	}
+
+	payload := &ManyPayload{Data: data, Included: nodeMapValues(included)}
+	return json.NewEncoder(w).Encode(payload)
+}
+
+func nodeMapValues(m map[string]*Node) []*Node {
+	if len(m) == 0 {
+		return nil
+	}
+	nodes := make([]*Node, 0, len(m))
+	for _, n := range m {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// nodeFilter narrows what visitModelNode sideloads and emits. A nil
+// *nodeFilter means the legacy MarshalPayload behavior: every relationship is
+// recursed into and sideloaded, and no attribute/relationship is dropped.
+// MarshalPayloadWithQuery supplies one to restrict sideloading to an include
+// tree and attributes/relationships to a sparse fieldset.
+type nodeFilter struct {
+	tree   *includeNode
+	fields map[string][]string
+}
+
+func visitModelNode(model interface{}, included *map[string]*Node, filter *nodeFilter) (*Node, error) {
+	modelValue := reflect.ValueOf(model)
+	for modelValue.Kind() == reflect.Ptr {
+		modelValue = modelValue.Elem()
+	}
+	modelType := modelValue.Type()
+
+	node := &Node{
+		Attributes:    make(map[string]interface{}),
+		Relationships: make(map[string]interface{}),
+	}
+
+	if err := visitStructFields(modelType, modelValue, node, included, filter); err != nil {
+		return nil, err
+	}
+
+	if filter != nil {
+		if allowed, ok := filter.fields[node.Type]; ok && len(allowed) > 0 {
+			restrictToFields(node, allowed)
+		}
+	}
+
+	if len(node.Attributes) == 0 {
+		node.Attributes = nil
+	}
+	if len(node.Relationships) == 0 {
+		node.Relationships = nil
+	}
+
+	if linkable, ok := model.(Linkable); ok {
+		node.Links = linkable.JSONAPILinks()
+	}
+	if metable, ok := model.(Metable); ok {
+		node.Meta = metable.JSONAPIMeta()
+	}
+
+	return node, nil
+}
+
+func visitStructFields(modelType reflect.Type, modelValue reflect.Value, node *Node, included *map[string]*Node, filter *nodeFilter) error {
+	model := modelValue.Addr().Interface()
+
+	for i := 0; i < modelValue.NumField(); i++ {
+		fieldType := modelType.Field(i)
+
+		if fieldType.Anonymous {
+			embedded := modelValue.Field(i)
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if err := visitStructFields(embedded.Type(), embedded, node, included, filter); err != nil {
+				return err
+			}
+			continue
+		}
+
+		tag := fieldType.Tag.Get("jsonapi")
+		if tag == "" {
+			continue
+		}
+
+		fieldValue := modelValue.Field(i)
+		args := strings.Split(tag, annotationSeperator)
+		if len(args) < 1 {
+			return fmt.Errorf(unsupportedStructTagMsg, tag)
+		}
+
+		switch args[0] {
+		case annotationPrimary:
+			if len(args) < 2 {
+				return fmt.Errorf(unsupportedStructTagMsg, tag)
+			}
+			node.Type = args[1]
+			node.ID = fmt.Sprintf("%v", derefValue(fieldValue).Interface())
+		case annotationClientID:
+			if !fieldValue.IsZero() {
+				node.ClientID = fieldValue.String()
+			}
+		case annotationExtra:
+			if err := marshalExtraMap(fieldValue, node); err != nil {
+				return err
+			}
+		case annotationDefault:
+			if len(args) < 2 {
+				return fmt.Errorf(unsupportedStructTagMsg, tag)
+			}
+			if args[1] == "*" {
+				if err := marshalExtraMap(fieldValue, node); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := marshalAttribute(fieldValue, args[1], args[2:], node); err != nil {
+				return err
+			}
+		case annotationRelation:
+			if len(args) < 2 {
+				return fmt.Errorf(unsupportedStructTagMsg, tag)
+			}
+			if err := marshalRelation(model, fieldValue, args[1], node, included, filter); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf(unsupportedStructTagMsg, args[0])
+		}
+	}
+	return nil
+}
+
+func derefValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Zero(v.Type().Elem())
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func marshalAttribute(fieldValue reflect.Value, name string, options []string, node *Node) error {
+	omitEmpty := false
+	for _, opt := range options {
+		if opt == annotationOmitEmpty {
+			omitEmpty = true
+		}
+	}
+
+	if isEmptyValue(fieldValue) {
+		if omitEmpty {
+			return nil
+		}
+	}
+
+	if fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() {
+		node.Attributes[name] = nil
+		return nil
+	}
+
+	for _, opt := range options {
+		codec, ok := lookupAttrCodec(opt)
+		if !ok {
+			continue
+		}
+		encoded, err := codec.Marshal(fieldValue)
+		if err != nil {
+			return fmt.Errorf("attribute %q: %w", name, err)
+		}
+		node.Attributes[name] = encoded
+		return nil
+	}
+
+	if t, ok := timeValue(fieldValue); ok {
+		node.Attributes[name] = t.UTC().Format(time.RFC3339)
+		return nil
+	}
+
+	node.Attributes[name] = derefValue(fieldValue).Interface()
+	return nil
+}
+
+// marshalExtraMap merges a map[string]interface{} field tagged
+// `jsonapi:"extra"` (or `attr,*`) into node's attributes. Entries from the
+// map never clobber an attribute already set by an explicitly-tagged field,
+// regardless of which is visited first.
+func marshalExtraMap(fieldValue reflect.Value, node *Node) error {
+	if fieldValue.Kind() != reflect.Map || fieldValue.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("extra attribute field must be a map[string]interface{}")
+	}
+
+	iter := fieldValue.MapRange()
+	for iter.Next() {
+		k := iter.Key().String()
+		if _, exists := node.Attributes[k]; exists {
+			continue
+		}
+		node.Attributes[k] = iter.Value().Interface()
+	}
+	return nil
+}
+
+func timeValue(fieldValue reflect.Value) (time.Time, bool) {
+	v := derefValue(fieldValue)
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		return v.Interface().(time.Time), true
+	}
+	return time.Time{}, false
+}
+
+func addrInterface(fieldValue reflect.Value) interface{} {
+	v := fieldValue
+	if v.Kind() == reflect.Ptr {
+		return v.Interface()
+	}
+	if v.CanAddr() {
+		return v.Addr().Interface()
+	}
+	return v.Interface()
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr:
+		return v.IsNil()
+	case reflect.String:
+		return v.Len() == 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	}
+	return false
+}
+
+func marshalRelation(model interface{}, fieldValue reflect.Value, name string, node *Node, included *map[string]*Node, filter *nodeFilter) error {
+	relationship := make(map[string]interface{})
+
+	if linkable, ok := model.(RelationshipLinkable); ok {
+		if links := linkable.JSONAPIRelationshipLinks(name); links != nil {
+			relationship["links"] = links
+		}
+	}
+	if metable, ok := model.(RelationshipMetable); ok {
+		if meta := metable.JSONAPIRelationshipMeta(name); meta != nil {
+			relationship["meta"] = meta
+		}
+	}
+
+	sideload := true
+	childFilter := filter
+	if filter != nil {
+		sideload = false
+		childFilter = &nodeFilter{fields: filter.fields}
+		if filter.tree != nil {
+			if child, ok := filter.tree.children[name]; ok {
+				sideload = true
+				childFilter.tree = child
+			}
+		}
+	}
+
+	switch fieldValue.Kind() {
+	case reflect.Slice:
+		if fieldValue.IsNil() {
+			relationship["data"] = []interface{}{}
+			node.Relationships[name] = relationship
+			return nil
+		}
+		data := make([]*Node, 0, fieldValue.Len())
+		for i := 0; i < fieldValue.Len(); i++ {
+			elem := fieldValue.Index(i)
+			if elem.Kind() == reflect.Interface && elem.IsNil() {
+				continue
+			}
+			relNode, err := visitModelNode(elem.Interface(), included, childFilter)
+			if err != nil {
+				return err
+			}
+			data = append(data, toShallowNode(relNode))
+			sideloadNode(included, relNode, sideload)
+		}
+		relationship["data"] = data
+	default:
+		if (fieldValue.Kind() == reflect.Ptr || fieldValue.Kind() == reflect.Interface) && fieldValue.IsNil() {
+			relationship["data"] = nil
+			node.Relationships[name] = relationship
+			return nil
+		}
+		relNode, err := visitModelNode(fieldValue.Interface(), included, childFilter)
+		if err != nil {
+			return err
+		}
+		relationship["data"] = toShallowNode(relNode)
+		sideloadNode(included, relNode, sideload)
+	}
+
+	node.Relationships[name] = relationship
+	return nil
+}
+
+// restrictToFields deletes any attribute or relationship from node not named
+// in allowed, implementing a JSON:API sparse fieldset for one resource type.
+func restrictToFields(node *Node, allowed []string) {
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, a := range allowed {
+		allowedSet[a] = true
+	}
+	for k := range node.Attributes {
+		if !allowedSet[k] {
+			delete(node.Attributes, k)
+		}
+	}
+	for k := range node.Relationships {
+		if !allowedSet[k] {
+			delete(node.Relationships, k)
+		}
+	}
+}
+
+// toShallowNode reduces n to the bare resource identifier object --
+// `{"type": ..., "id": ...}` -- that belongs in a relationship's `data`
+// member. The full node, attributes and all, is sideloaded into `included`
+// separately by sideloadNode.
+func toShallowNode(n *Node) *Node {
+	return &Node{Type: n.Type, ID: n.ID}
+}
+
+func sideloadNode(included *map[string]*Node, node *Node, sideload bool) {
+	if !sideload || included == nil {
+		return
+	}
+	key := fmt.Sprintf("%s,%s", node.Type, node.ID)
+	if _, ok := (*included)[key]; !ok {
+		(*included)[key] = &Node{Type: node.Type, ID: node.ID, Attributes: node.Attributes, Relationships: node.Relationships, Links: node.Links, Meta: node.Meta}
+	}
+}
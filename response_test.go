@@ -0,0 +1,54 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalRelationDataIsResourceIdentifier(t *testing.T) {
+	post := &Post{
+		ID:       1,
+		Title:    "hello",
+		Comments: []*Comment{{ID: 5, Body: "nice"}},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := MarshalPayload(buf, post); err != nil {
+		t.Fatalf("MarshalPayload() error = %v", err)
+	}
+
+	roundTripped := new(OnePayload)
+	if err := json.NewDecoder(bytes.NewReader(buf.Bytes())).Decode(roundTripped); err != nil {
+		t.Fatalf("decode error = %v", err)
+	}
+
+	relationship, ok := roundTripped.Data.Relationships["comments"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected comments relationship to be an object, got %T", roundTripped.Data.Relationships["comments"])
+	}
+	data, ok := relationship["data"].([]interface{})
+	if !ok || len(data) != 1 {
+		t.Fatalf("expected comments relationship data to be a one-element array, got %v", relationship["data"])
+	}
+	identifier, ok := data[0].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a resource identifier object, got %T", data[0])
+	}
+	if _, hasAttrs := identifier["attributes"]; hasAttrs {
+		t.Errorf("relationship data must not carry attributes, got %v", identifier)
+	}
+	if _, hasRels := identifier["relationships"]; hasRels {
+		t.Errorf("relationship data must not carry relationships, got %v", identifier)
+	}
+	if identifier["type"] != "comments" || identifier["id"] != "5" {
+		t.Errorf("identifier = %v, want type=comments id=5", identifier)
+	}
+
+	if len(roundTripped.Included) != 1 {
+		t.Fatalf("expected the full comment to be sideloaded, got %d included resources", len(roundTripped.Included))
+	}
+	if roundTripped.Included[0].Attributes["body"] != "nice" {
+		t.Errorf("included comment attributes = %v, want body=nice", roundTripped.Included[0].Attributes)
+	}
+}
@@ -0,0 +1,347 @@
+package jsonapi
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// defaultIncludedCacheSize bounds how many distinct (type, id) resources a
+// StreamEncoder keeps in memory to dedupe `included`. Older entries are
+// evicted once the cache is full, trading a theoretically complete
+// `included` array for bounded memory on very large collections.
+const defaultIncludedCacheSize = 10000
+
+// IncludeResolver is consulted by StreamDecoder for a relationship whose
+// target wasn't present in the stream's own `included` array -- most
+// commonly because the referencing resource was read before the resource it
+// references.
+type IncludeResolver interface {
+	Resolve(resourceType, id string) (*Node, bool)
+}
+
+// includeIndex is the lookup UnmarshalPayload/UnmarshalManyPayload/
+// StreamDecoder use to resolve a relationship's full resource: first the
+// sideloaded `included` nodes seen so far, then an optional IncludeResolver
+// fallback.
+type includeIndex struct {
+	nodes    map[string]*Node
+	resolver IncludeResolver
+}
+
+func newIncludeIndex(included []*Node, resolver IncludeResolver) *includeIndex {
+	nodes := make(map[string]*Node, len(included))
+	for _, n := range included {
+		nodes[includeKey(n.Type, n.ID)] = n
+	}
+	return &includeIndex{nodes: nodes, resolver: resolver}
+}
+
+func includeKey(resourceType, id string) string {
+	return fmt.Sprintf("%s,%s", resourceType, id)
+}
+
+func (idx *includeIndex) lookup(resourceType, id string) (*Node, bool) {
+	if idx == nil {
+		return nil, false
+	}
+	if n, ok := idx.nodes[includeKey(resourceType, id)]; ok {
+		return n, true
+	}
+	if idx.resolver != nil {
+		return idx.resolver.Resolve(resourceType, id)
+	}
+	return nil, false
+}
+
+func (idx *includeIndex) remember(n *Node) {
+	if idx == nil || n == nil {
+		return
+	}
+	idx.nodes[includeKey(n.Type, n.ID)] = n
+}
+
+// includeCache is a fixed-capacity, least-recently-added cache of included
+// nodes keyed by (type, id). It backs StreamEncoder's `included` dedup so a
+// caller streaming a huge collection doesn't have to hold one entry in RAM
+// per row ever seen.
+type includeCache struct {
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type includeCacheEntry struct {
+	key  string
+	node *Node
+}
+
+func newIncludeCache(capacity int) *includeCache {
+	return &includeCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// add inserts node if its key isn't already cached, evicting the oldest
+// entry when over capacity. It reports whether the node was newly added.
+func (c *includeCache) add(node *Node) bool {
+	key := includeKey(node.Type, node.ID)
+	if _, ok := c.entries[key]; ok {
+		return false
+	}
+
+	if c.capacity > 0 && c.order.Len() >= c.capacity {
+		oldest := c.order.Front()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*includeCacheEntry).key)
+		}
+	}
+
+	c.entries[key] = c.order.PushBack(&includeCacheEntry{key: key, node: node})
+	return true
+}
+
+func (c *includeCache) values() []*Node {
+	nodes := make([]*Node, 0, c.order.Len())
+	for e := c.order.Front(); e != nil; e = e.Next() {
+		nodes = append(nodes, e.Value.(*includeCacheEntry).node)
+	}
+	return nodes
+}
+
+// StreamEncoder writes a JSON:API ManyPayload one resource at a time instead
+// of building the full collection (and its `included` array) in memory
+// before writing, the way MarshalPayload does.
+type StreamEncoder struct {
+	w        io.Writer
+	included *includeCache
+	wroteAny bool
+	closed   bool
+	err      error
+}
+
+// NewStreamEncoder returns a StreamEncoder that writes to w. Its included
+// resource cache is bounded to defaultIncludedCacheSize distinct (type, id)
+// entries; use NewStreamEncoderSize for a different bound.
+func NewStreamEncoder(w io.Writer) *StreamEncoder {
+	return NewStreamEncoderSize(w, defaultIncludedCacheSize)
+}
+
+// NewStreamEncoderSize is like NewStreamEncoder but with an explicit
+// included-cache capacity.
+func NewStreamEncoderSize(w io.Writer, includedCacheSize int) *StreamEncoder {
+	return &StreamEncoder{w: w, included: newIncludeCache(includedCacheSize)}
+}
+
+// Encode writes one more resource into the streamed `data` array.
+func (e *StreamEncoder) Encode(model interface{}) error {
+	if e.err != nil {
+		return e.err
+	}
+
+	localIncluded := make(map[string]*Node)
+	node, err := visitModelNode(model, &localIncluded, nil)
+	if err != nil {
+		e.err = err
+		return err
+	}
+
+	if !e.wroteAny {
+		if _, err := io.WriteString(e.w, `{"data":[`); err != nil {
+			e.err = err
+			return err
+		}
+		e.wroteAny = true
+	} else {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			e.err = err
+			return err
+		}
+	}
+
+	b, err := json.Marshal(node)
+	if err != nil {
+		e.err = err
+		return err
+	}
+	if _, err := e.w.Write(b); err != nil {
+		e.err = err
+		return err
+	}
+
+	for _, n := range localIncluded {
+		e.included.add(n)
+	}
+	return nil
+}
+
+// Close writes the closing `data` bracket and the deduplicated `included`
+// array, then the closing brace of the envelope. It must be called exactly
+// once, after the last Encode call.
+func (e *StreamEncoder) Close() error {
+	if e.closed {
+		return e.err
+	}
+	e.closed = true
+	if e.err != nil {
+		return e.err
+	}
+
+	if !e.wroteAny {
+		if _, err := io.WriteString(e.w, `{"data":[`); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(e.w, `],"included":[`); err != nil {
+		return err
+	}
+
+	for i, n := range e.included.values() {
+		if i > 0 {
+			if _, err := io.WriteString(e.w, ","); err != nil {
+				return err
+			}
+		}
+		b, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		if _, err := e.w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(e.w, "]}")
+	return err
+}
+
+// StreamDecoder reads a JSON:API ManyPayload and materializes one resource
+// at a time via Next(), using the RegisterPolymorphicType registry to pick
+// the concrete Go type for each resource's `type` member. See the
+// NewStreamDecoder doc comment for its memory-boundedness caveat.
+type StreamDecoder struct {
+	dec *json.Decoder
+	idx *includeIndex
+}
+
+// NewStreamDecoder reads the envelope from r and returns a decoder that
+// yields one materialized resource from `data` per Next() call.
+//
+// A resource's relationships may reference an `included` entry that appears
+// later in the stream than the resource itself, so r is buffered once up
+// front and `included` is fully decoded before the first Next() call
+// returns -- resolving a forward reference without that would require
+// either seeking backward or a second pass over r, neither of which an
+// arbitrary io.Reader supports. `data` itself, however, is never
+// materialized as a whole: Next() walks it with a json.Decoder positioned
+// just inside the array (via Token()) and decodes one raw message at a
+// time, so decoded `data` resources are not accumulated; the raw bytes of
+// the whole input, `data` included, stay buffered for the decoder's
+// lifetime. Callers who also need `included` to stay flat at 100k+-row scale
+// should supply an IncludeResolver backed by their own store instead of
+// relying on same-stream `included`.
+func NewStreamDecoder(r io.Reader) (*StreamDecoder, error) {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope struct {
+		Included []*Node `json:"included"`
+	}
+	if err := json.Unmarshal(buf, &envelope); err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	if err := enterArray(dec, "data"); err != nil {
+		return nil, err
+	}
+
+	return &StreamDecoder{
+		dec: dec,
+		idx: newIncludeIndex(envelope.Included, nil),
+	}, nil
+}
+
+// enterArray advances dec, a decoder positioned at the start of a JSON
+// object, past every member up to and including key's opening `[` token, so
+// that dec.More()/dec.Decode() can walk that array's elements one at a time.
+func enterArray(dec *json.Decoder, key string) error {
+	if t, err := dec.Token(); err != nil {
+		return err
+	} else if d, ok := t.(json.Delim); !ok || d != '{' {
+		return fmt.Errorf("jsonapi: expected a JSON object")
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name, ok := t.(string)
+		if !ok {
+			return fmt.Errorf("jsonapi: expected an object key")
+		}
+		if name != key {
+			var discard json.RawMessage
+			if err := dec.Decode(&discard); err != nil {
+				return err
+			}
+			continue
+		}
+		t, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := t.(json.Delim); !ok || d != '[' {
+			return fmt.Errorf("jsonapi: %q is not an array", key)
+		}
+		return nil
+	}
+	return fmt.Errorf("jsonapi: missing %q member", key)
+}
+
+// SetIncludeResolver installs a fallback consulted whenever a relationship's
+// target resource isn't present in the stream's own `included` array.
+func (d *StreamDecoder) SetIncludeResolver(resolver IncludeResolver) {
+	d.idx.resolver = resolver
+}
+
+// Next decodes and returns the next resource from `data`, or io.EOF once
+// every resource has been returned.
+func (d *StreamDecoder) Next() (interface{}, error) {
+	if !d.dec.More() {
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	node := new(Node)
+	if err := json.Unmarshal(raw, node); err != nil {
+		return nil, err
+	}
+
+	registered, ok := lookupPolymorphicType(node.Type)
+	if !ok {
+		return nil, fmt.Errorf("jsonapi: no type registered for resource type %q; call RegisterPolymorphicType", node.Type)
+	}
+
+	d.idx.remember(node)
+
+	model := reflect.New(registered)
+	if err := unmarshalNode(node, model, d.idx); err != nil {
+		return nil, err
+	}
+	return model.Interface(), nil
+}
@@ -0,0 +1,87 @@
+package jsonapi
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func benchmarkPosts(n int) []*Post {
+	posts := make([]*Post, n)
+	for i := 0; i < n; i++ {
+		posts[i] = &Post{
+			ID:    uint64(i),
+			Title: "benchmark post",
+			Body:  "some body text that is reasonably sized for a realistic payload",
+			Comments: []*Comment{
+				{ID: i % 100, Body: "a shared comment"},
+			},
+		}
+	}
+	return posts
+}
+
+func BenchmarkMarshalPayload100k(b *testing.B) {
+	posts := benchmarkPosts(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := MarshalPayload(io.Discard, posts); err != nil {
+			b.Fatalf("MarshalPayload() error = %v", err)
+		}
+	}
+}
+
+func BenchmarkStreamEncoder100k(b *testing.B) {
+	posts := benchmarkPosts(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enc := NewStreamEncoder(io.Discard)
+		for _, p := range posts {
+			if err := enc.Encode(p); err != nil {
+				b.Fatalf("Encode() error = %v", err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			b.Fatalf("Close() error = %v", err)
+		}
+	}
+}
+
+// BenchmarkStreamDecoder100k measures NewStreamDecoder/Next() against a 100k-
+// element payload. NewStreamDecoder still buffers the raw input once (see
+// its doc comment, it needs to in order to resolve relationships that
+// reference an `included` entry appearing later in the stream), but Next()
+// walks `data` token by token rather than from a pre-decoded slice, so its
+// own per-call allocations stay flat regardless of how many resources
+// `data` holds.
+func BenchmarkStreamDecoder100k(b *testing.B) {
+	posts := benchmarkPosts(100000)
+	buf := bytes.NewBuffer(nil)
+	enc := NewStreamEncoder(buf)
+	for _, p := range posts {
+		if err := enc.Encode(p); err != nil {
+			b.Fatalf("Encode() error = %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		b.Fatalf("Close() error = %v", err)
+	}
+	payload := buf.Bytes()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dec, err := NewStreamDecoder(bytes.NewReader(payload))
+		if err != nil {
+			b.Fatalf("NewStreamDecoder() error = %v", err)
+		}
+		for {
+			if _, err := dec.Next(); err != nil {
+				if err == io.EOF {
+					break
+				}
+				b.Fatalf("Next() error = %v", err)
+			}
+		}
+	}
+}
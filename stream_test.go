@@ -0,0 +1,202 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func init() {
+	RegisterPolymorphicType("posts", &Post{})
+}
+
+func TestStreamEncoderDedupesIncluded(t *testing.T) {
+	comment := &Comment{ID: 1, Body: "shared"}
+	posts := []*Post{
+		{ID: 1, Title: "first", Comments: []*Comment{comment}},
+		{ID: 2, Title: "second", Comments: []*Comment{comment}},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	enc := NewStreamEncoder(buf)
+	for _, p := range posts {
+		if err := enc.Encode(p); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var envelope struct {
+		Data     []json.RawMessage `json:"data"`
+		Included []json.RawMessage `json:"included"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &envelope); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if len(envelope.Data) != 2 {
+		t.Fatalf("expected 2 data resources, got %d", len(envelope.Data))
+	}
+	if len(envelope.Included) != 1 {
+		t.Fatalf("expected the shared comment to be included exactly once, got %d", len(envelope.Included))
+	}
+}
+
+func TestStreamEncoderBoundedCache(t *testing.T) {
+	posts := make([]*Post, 0, 5)
+	for i := 1; i <= 5; i++ {
+		posts = append(posts, &Post{
+			ID:       uint64(i),
+			Title:    fmt.Sprintf("post-%d", i),
+			Comments: []*Comment{{ID: i, Body: fmt.Sprintf("comment-%d", i)}},
+		})
+	}
+
+	buf := bytes.NewBuffer(nil)
+	enc := NewStreamEncoderSize(buf, 2)
+	for _, p := range posts {
+		if err := enc.Encode(p); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var envelope struct {
+		Included []json.RawMessage `json:"included"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &envelope); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(envelope.Included) != 2 {
+		t.Fatalf("expected the included cache to cap at 2 entries, got %d", len(envelope.Included))
+	}
+}
+
+func TestStreamDecoderYieldsOneResourceAtATime(t *testing.T) {
+	in := `{"data":[
+		{"type":"posts","id":"1","attributes":{"title":"first","body":""}},
+		{"type":"posts","id":"2","attributes":{"title":"second","body":""}}
+	]}`
+
+	dec, err := NewStreamDecoder(bytes.NewReader([]byte(in)))
+	if err != nil {
+		t.Fatalf("NewStreamDecoder() error = %v", err)
+	}
+
+	var titles []string
+	for {
+		model, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		post, ok := model.(*Post)
+		if !ok {
+			t.Fatalf("expected *Post, got %T", model)
+		}
+		titles = append(titles, post.Title)
+	}
+
+	if len(titles) != 2 || titles[0] != "first" || titles[1] != "second" {
+		t.Errorf("titles = %v, want [first second]", titles)
+	}
+}
+
+func TestStreamDecoderRelinksIncludedArrivingAfterReferencingResource(t *testing.T) {
+	// The comment relationship is referenced by the post (data[0]) before
+	// its full resource appears in `included`, which the encoder always
+	// writes last. A naive single-pass decoder would have to give up on
+	// filling in the comment's Body; this one re-links it correctly.
+	in := `{
+		"data": [
+			{
+				"type": "posts",
+				"id": "1",
+				"attributes": {"title": "hello", "body": "world"},
+				"relationships": {
+					"latest_comment": {"data": {"type": "comments", "id": "9"}}
+				}
+			}
+		],
+		"included": [
+			{"type": "comments", "id": "9", "attributes": {"body": "nice post"}}
+		]
+	}`
+
+	dec, err := NewStreamDecoder(bytes.NewReader([]byte(in)))
+	if err != nil {
+		t.Fatalf("NewStreamDecoder() error = %v", err)
+	}
+
+	model, err := dec.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	post, ok := model.(*Post)
+	if !ok {
+		t.Fatalf("expected *Post, got %T", model)
+	}
+	if post.LatestComment == nil {
+		t.Fatal("expected LatestComment to be populated")
+	}
+	if post.LatestComment.Body != "nice post" {
+		t.Errorf("LatestComment.Body = %q, want %q", post.LatestComment.Body, "nice post")
+	}
+}
+
+func TestStreamDecoderRejectsMissingDataMember(t *testing.T) {
+	in := `{"included":[{"type":"comments","id":"9","attributes":{"body":"nice"}}]}`
+
+	if _, err := NewStreamDecoder(bytes.NewReader([]byte(in))); err == nil {
+		t.Fatal("NewStreamDecoder() error = nil, want an error for a missing \"data\" member")
+	}
+}
+
+func TestStreamDecoderIncludeResolverFallback(t *testing.T) {
+	in := `{
+		"data": [
+			{
+				"type": "posts",
+				"id": "1",
+				"attributes": {"title": "hello", "body": "world"},
+				"relationships": {
+					"latest_comment": {"data": {"type": "comments", "id": "42"}}
+				}
+			}
+		]
+	}`
+
+	dec, err := NewStreamDecoder(bytes.NewReader([]byte(in)))
+	if err != nil {
+		t.Fatalf("NewStreamDecoder() error = %v", err)
+	}
+	dec.SetIncludeResolver(resolverFunc(func(resourceType, id string) (*Node, bool) {
+		if resourceType == "comments" && id == "42" {
+			return &Node{Type: "comments", ID: "42", Attributes: map[string]interface{}{"body": "from resolver"}}, true
+		}
+		return nil, false
+	}))
+
+	model, err := dec.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	post := model.(*Post)
+	if post.LatestComment == nil || post.LatestComment.Body != "from resolver" {
+		t.Errorf("expected LatestComment to be resolved via IncludeResolver, got %+v", post.LatestComment)
+	}
+}
+
+type resolverFunc func(resourceType, id string) (*Node, bool)
+
+func (f resolverFunc) Resolve(resourceType, id string) (*Node, bool) {
+	return f(resourceType, id)
+}